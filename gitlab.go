@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/sync/errgroup"
+
+	"weeports/logging"
+)
+
+const (
+	defaultMaxConcurrency = 4
+	defaultPerPage        = 100
+	maxRetries            = 5
+	initialBackoff        = 500 * time.Millisecond
+)
+
+// gitlabFetcher bounds how much concurrent work weeports does against the
+// GitLab API at once, so a report with many projects doesn't open a
+// connection per project.
+type gitlabFetcher struct {
+	maxConcurrency int
+}
+
+func newGitlabFetcher(config Config) *gitlabFetcher {
+	maxConcurrency := config.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+
+	return &gitlabFetcher{maxConcurrency: maxConcurrency}
+}
+
+// forEach runs work for every item, at most f.maxConcurrency at a time, and
+// returns the first error encountered (if any), after every goroutine has
+// finished. It stops dispatching new work once ctx is cancelled.
+func (f *gitlabFetcher) forEach(ctx context.Context, items []int, work func(ctx context.Context, item int) error) error {
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(f.maxConcurrency)
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		item := item
+		group.Go(func() error {
+			return work(ctx, item)
+		})
+	}
+
+	return group.Wait()
+}
+
+// retryAfter returns how long to wait before retrying a GitLab request,
+// honoring the Retry-After / RateLimit-Reset headers when present, or
+// falling back to attempt's exponential backoff otherwise.
+func retryAfter(response *gitlab.Response, attempt int) time.Duration {
+	backoff := initialBackoff << attempt
+
+	if response == nil {
+		return backoff
+	}
+
+	if retryAfterHeader := response.Header.Get("Retry-After"); retryAfterHeader != "" {
+		if seconds, err := strconv.Atoi(retryAfterHeader); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if resetHeader := response.Header.Get("RateLimit-Reset"); resetHeader != "" {
+		if resetUnix, err := strconv.ParseInt(resetHeader, 10, 64); err == nil {
+			if wait := time.Until(time.Unix(resetUnix, 0)); wait > 0 {
+				return wait
+			}
+		}
+	}
+
+	return backoff
+}
+
+func isRetryable(response *gitlab.Response, err error) bool {
+	if err != nil && response == nil {
+		return true
+	}
+	if response == nil {
+		return false
+	}
+
+	return response.StatusCode == 429 || response.StatusCode >= 500
+}
+
+// withRetry calls request, retrying with backoff on transient failures
+// (429s, 5xxs, and network errors), making at most maxRetries attempts in
+// all. It gives up
+// early, returning ctx.Err(), if ctx is cancelled while waiting to retry.
+func withRetry[T any](ctx context.Context, request func() (T, *gitlab.Response, error)) (T, *gitlab.Response, error) {
+	var (
+		result   T
+		response *gitlab.Response
+		err      error
+	)
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		result, response, err = request()
+		if err == nil && (response == nil || response.StatusCode/100 == 2) {
+			return result, response, nil
+		}
+		if !isRetryable(response, err) || attempt == maxRetries-1 {
+			return result, response, err
+		}
+
+		wait := retryAfter(response, attempt)
+		logging.Warnf("GitLab request failed (attempt %d/%d), retrying in %s: %v", attempt+1, maxRetries, wait, err)
+		select {
+		case <-ctx.Done():
+			return result, response, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return result, response, err
+}
+
+// paginate walks fetchPage's response.NextPage until it's exhausted,
+// retrying each page on transient failures, and returns every item across
+// all pages.
+func paginate[T any](ctx context.Context, fetchPage func(page int) ([]T, *gitlab.Response, error)) ([]T, error) {
+	var all []T
+	page := 1
+
+	for {
+		items, response, err := withRetry(ctx, func() ([]T, *gitlab.Response, error) {
+			return fetchPage(page)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, items...)
+		if response == nil || response.NextPage == 0 {
+			break
+		}
+		page = response.NextPage
+	}
+
+	return all, nil
+}
@@ -0,0 +1,165 @@
+// Package logging provides level-based logging for weeports. Loggers are
+// no-ops by default so that interactive, one-shot runs stay quiet; they
+// switch on automatically once stdout is no longer a terminal (e.g. when
+// running under cron or with stdout redirected to a file), and can always be configured explicitly
+// with -log-level / -log-file.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is a logging verbosity level, ordered from least to most verbose.
+type Level int
+
+const (
+	LevelOff Level = iota
+	LevelFatal
+	LevelError
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelOff:
+		return "OFF"
+	case LevelFatal:
+		return "FATAL"
+	case LevelError:
+		return "ERROR"
+	case LevelWarn:
+		return "WARN"
+	case LevelInfo:
+		return "INFO"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelTrace:
+		return "TRACE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseLevel parses a level name such as "info" or "DEBUG".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "OFF":
+		return LevelOff, nil
+	case "FATAL":
+		return LevelFatal, nil
+	case "ERROR":
+		return LevelError, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "DEBUG":
+		return LevelDebug, nil
+	case "TRACE":
+		return LevelTrace, nil
+	default:
+		return LevelOff, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+var (
+	mu     sync.Mutex
+	level  = LevelOff
+	output io.Writer = os.Stderr
+)
+
+// stdoutRedirected reports whether stdout is not an interactive terminal,
+// which is the signal used to auto-enable logging for unattended runs.
+func stdoutRedirected() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) == 0
+}
+
+// Configure sets the active log level and output destination. levelName may
+// be empty, in which case the level defaults to Info when stdout is
+// redirected (unattended run) or Off otherwise (interactive run). filePath
+// may be empty, in which case output goes to stderr.
+func Configure(levelName, filePath string) error {
+	newLevel := LevelOff
+	if levelName != "" {
+		parsed, err := ParseLevel(levelName)
+		if err != nil {
+			return err
+		}
+		newLevel = parsed
+	} else if stdoutRedirected() {
+		newLevel = LevelInfo
+	}
+
+	newOutput := io.Writer(os.Stderr)
+	if filePath != "" {
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("logging: opening log file: %w", err)
+		}
+		newOutput = file
+	}
+
+	mu.Lock()
+	level = newLevel
+	output = newOutput
+	mu.Unlock()
+
+	return nil
+}
+
+func enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l <= level
+}
+
+func logf(l Level, format string, args ...any) {
+	if !enabled(l) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	fmt.Fprintf(output, "%s [%s] %s\n", time.Now().Format(time.RFC3339), l, fmt.Sprintf(format, args...))
+}
+
+func Tracef(format string, args ...any) { logf(LevelTrace, format, args...) }
+func Debugf(format string, args ...any) { logf(LevelDebug, format, args...) }
+func Infof(format string, args ...any)  { logf(LevelInfo, format, args...) }
+func Warnf(format string, args ...any)  { logf(LevelWarn, format, args...) }
+func Errorf(format string, args ...any) { logf(LevelError, format, args...) }
+
+// Fatalf logs at the Fatal level and terminates the process, mirroring
+// log.Fatalf. It should only be used for conditions that make it pointless
+// to continue running at all, never for transient, per-run errors.
+func Fatalf(format string, args ...any) {
+	if enabled(LevelFatal) {
+		logf(LevelFatal, format, args...)
+	} else {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+	os.Exit(1)
+}
+
+// PanicHandler logs and re-raises a panic recovered from the calling
+// goroutine. Intended to be deferred at the top of main:
+//
+//	defer logging.PanicHandler()
+func PanicHandler() {
+	if r := recover(); r != nil {
+		logf(LevelFatal, "panic: %v", r)
+		panic(r)
+	}
+}
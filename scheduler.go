@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"weeports/logging"
+)
+
+// scheduler runs a function repeatedly according to a cron-like schedule,
+// recomputing the next fire time after every run instead of ticking at a
+// fixed interval.
+type scheduler struct {
+	schedule cron.Schedule
+	location *time.Location
+	runFunc  func(ctx context.Context) error
+}
+
+func newScheduler(scheduleExpr, timezone string, runFunc func(ctx context.Context) error) (*scheduler, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := cron.ParseStandard(scheduleExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduler{schedule: schedule, location: location, runFunc: runFunc}, nil
+}
+
+// Run blocks, firing runFunc at each scheduled occurrence, until ctx is
+// cancelled.
+func (s *scheduler) Run(ctx context.Context) {
+	for {
+		now := time.Now().In(s.location)
+		next := s.schedule.Next(now)
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			err := s.runFunc(ctx)
+			if err != nil {
+				logging.Errorf("scheduled run at %s failed: %v", next, err)
+			} else {
+				logging.Infof("scheduled run at %s completed", next)
+			}
+		}
+	}
+}
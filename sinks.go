@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// ReportSink delivers a rendered report to some destination.
+type ReportSink interface {
+	Send(subject, body string) error
+}
+
+// htmlSender is implemented by sinks that can send a plaintext body
+// alongside its HTML rendering, e.g. as a multipart/alternative email.
+// Sinks that don't implement it just get the plaintext rendering as their body.
+type htmlSender interface {
+	SendHTML(subject, plainBody, htmlBody string) error
+}
+
+// SinkConfig selects and configures one ReportSink entry from the config file.
+type SinkConfig struct {
+	Type        string
+	Directory   string
+	WebhookURL  string
+	IMAPHost    string
+	IMAPPort    string
+	IMAPUseTLS  bool
+	IMAPMailbox string
+}
+
+func buildSinks(config Config) ([]ReportSink, error) {
+	if len(config.Sinks) == 0 {
+		return []ReportSink{newSMTPSink(config)}, nil
+	}
+
+	var sinks []ReportSink
+	for _, sinkConfig := range config.Sinks {
+		switch sinkConfig.Type {
+		case "smtp":
+			sinks = append(sinks, newSMTPSink(config))
+		case "file":
+			sinks = append(sinks, &FileSink{directory: sinkConfig.Directory})
+		case "webhook":
+			sinks = append(sinks, &WebhookSink{url: sinkConfig.WebhookURL})
+		case "imap":
+			sinks = append(sinks, &IMAPAppendSink{
+				host:     sinkConfig.IMAPHost,
+				port:     sinkConfig.IMAPPort,
+				useTLS:   sinkConfig.IMAPUseTLS,
+				mailbox:  sinkConfig.IMAPMailbox,
+				username: config.SMTPUsername,
+				password: config.SMTPPassword,
+			})
+		default:
+			return nil, fmt.Errorf("unknown sink type %q in config", sinkConfig.Type)
+		}
+	}
+
+	return sinks, nil
+}
+
+// SMTPSink sends the report as an email, as weeports has always done.
+type SMTPSink struct {
+	host, port         string
+	username, password string
+	to                 string
+}
+
+func newSMTPSink(config Config) *SMTPSink {
+	return &SMTPSink{
+		host:     config.SMTPHost,
+		port:     config.SMTPPort,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+		to:       config.RecipientEmail,
+	}
+}
+
+func (s *SMTPSink) Send(subject, body string) error {
+	message := []byte("To: " + s.to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"\r\n" + body + "\r\n")
+
+	return s.sendRaw(message)
+}
+
+// SendHTML sends subject/plainBody/htmlBody as a multipart/alternative MIME
+// message, so mail clients that render HTML show htmlBody and the rest fall
+// back to plainBody.
+func (s *SMTPSink) SendHTML(subject, plainBody, htmlBody string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := "To: " + s.to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary() + "\r\n\r\n"
+	body.WriteString(header)
+
+	plainPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+	_, err = plainPart.Write([]byte(plainBody))
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+
+	htmlPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+	_, err = htmlPart.Write([]byte(htmlBody))
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+
+	return s.sendRaw(body.Bytes())
+}
+
+func (s *SMTPSink) sendRaw(message []byte) error {
+	auth := smtp.PlainAuth("", s.username, s.password, s.host)
+	err := smtp.SendMail(s.host+":"+s.port, auth, s.username, []string{s.to}, message)
+	if err != nil {
+		return fmt.Errorf("SMTPSink: %w", err)
+	}
+
+	return nil
+}
+
+// FileSink writes the report to a dated Markdown file in a directory.
+type FileSink struct {
+	directory string
+}
+
+func (s *FileSink) Send(subject, body string) error {
+	err := os.MkdirAll(s.directory, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("FileSink: %w", err)
+	}
+
+	fileName := time.Now().Format("2006-01-02") + ".md"
+	filePath := filepath.Join(s.directory, fileName)
+	contents := "# " + subject + "\n\n" + body
+	err = os.WriteFile(filePath, []byte(contents), 0644)
+	if err != nil {
+		return fmt.Errorf("FileSink: %w", err)
+	}
+
+	return nil
+}
+
+// WebhookSink POSTs the report as JSON to a Mattermost/Slack/Matrix incoming webhook.
+type WebhookSink struct {
+	url string
+}
+
+func (s *WebhookSink) Send(subject, body string) error {
+	payload, err := json.Marshal(map[string]string{"text": "**" + subject + "**\n\n" + body})
+	if err != nil {
+		return fmt.Errorf("WebhookSink: %w", err)
+	}
+
+	response, err := http.Post(s.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("WebhookSink: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return fmt.Errorf("WebhookSink: webhook returned status %s", response.Status)
+	}
+
+	return nil
+}
+
+// IMAPAppendSink appends the report as a draft message to an IMAP Drafts folder,
+// so it can be reviewed and edited before actually sending it.
+type IMAPAppendSink struct {
+	host, port         string
+	useTLS             bool
+	username, password string
+	mailbox            string
+}
+
+func (s *IMAPAppendSink) Send(subject, body string) error {
+	var imapClient *client.Client
+	var err error
+	addr := s.host + ":" + s.port
+	if s.useTLS {
+		imapClient, err = client.DialTLS(addr, nil)
+	} else {
+		imapClient, err = client.Dial(addr)
+	}
+	if err != nil {
+		return fmt.Errorf("IMAPAppendSink: %w", err)
+	}
+	defer imapClient.Logout()
+
+	err = imapClient.Login(s.username, s.password)
+	if err != nil {
+		return fmt.Errorf("IMAPAppendSink: %w", err)
+	}
+
+	mailbox := s.mailbox
+	if mailbox == "" {
+		mailbox = "Drafts"
+	}
+
+	message := "To: " + s.username + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"\r\n" + body + "\r\n"
+	err = imapClient.Append(mailbox, []string{imap.DraftFlag}, time.Now(), bytes.NewBufferString(message))
+	if err != nil {
+		return fmt.Errorf("IMAPAppendSink: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+// ProjectIssuesData groups the issues belonging to one project for rendering.
+type ProjectIssuesData struct {
+	ProjectName string
+	Issues      []IssueData
+}
+
+// IssueData is a single issue entry, with its merge request fields left
+// empty when the issue has none.
+type IssueData struct {
+	Title             string
+	URL               string
+	DueDate           string
+	MergeRequestTitle string
+	MergeRequestURL   string
+}
+
+// MergeRequestData is a single merge request entry.
+type MergeRequestData struct {
+	Title string
+	URL   string
+}
+
+// CommitStatData is the commit/line-change totals for one project.
+type CommitStatData struct {
+	ProjectName string
+	Commits     int
+	Additions   int
+	Deletions   int
+}
+
+// ReportData is everything a weekly report template can render.
+type ReportData struct {
+	ClosedWeeksLabel      string
+	ClosedIssues          []ProjectIssuesData
+	ToCloseIssues         []ProjectIssuesData
+	MergedWeeksLabel      string
+	MergedMergeRequests   []MergeRequestData
+	ReviewedMergeRequests []MergeRequestData
+	CommitStats           []CommitStatData
+	MainDifficulties      []string
+}
+
+const defaultMarkdownTemplate = `{{- if .ClosedIssues}}
+### Issues closed {{.ClosedWeeksLabel}}:
+
+{{range .ClosedIssues}}#### {{.ProjectName}}:
+{{range .Issues}}  * [{{.Title}}]({{.URL}})
+{{- if .DueDate}}
+    * Due date: {{.DueDate}}
+{{- end}}
+{{- if .MergeRequestTitle}}
+    * Merge request: [{{.MergeRequestTitle}}]({{.MergeRequestURL}})
+{{- end}}
+{{end}}
+{{end}}
+{{- end}}
+{{- if .ToCloseIssues}}
+### Issues to close this week:
+
+{{range .ToCloseIssues}}#### {{.ProjectName}}:
+{{range .Issues}}  * [{{.Title}}]({{.URL}})
+{{- if .DueDate}}
+    * Due date: {{.DueDate}}
+{{- end}}
+{{- if .MergeRequestTitle}}
+    * Merge request: [{{.MergeRequestTitle}}]({{.MergeRequestURL}})
+{{- end}}
+{{end}}
+{{end}}
+{{- end}}
+{{- if .MergedMergeRequests}}
+### Merge requests merged {{.MergedWeeksLabel}}:
+
+{{range .MergedMergeRequests}}  * [{{.Title}}]({{.URL}})
+{{end}}
+{{- end}}
+{{- if .ReviewedMergeRequests}}
+### Reviews performed:
+
+{{range .ReviewedMergeRequests}}  * [{{.Title}}]({{.URL}})
+{{end}}
+{{- end}}
+{{- if .CommitStats}}
+### Contribution stats:
+
+{{range .CommitStats}}  * {{.ProjectName}}: {{.Commits}} commits, +{{.Additions}}/-{{.Deletions}} lines
+{{end}}
+{{- end}}
+{{- if .MainDifficulties}}
+### Main difficulties:
+{{range .MainDifficulties}}  * {{.}}
+{{end}}
+{{- end}}
+`
+
+const defaultTextTemplate = `{{- if .ClosedIssues}}
+Issues closed {{.ClosedWeeksLabel}}:
+
+{{range .ClosedIssues}}{{.ProjectName}}:
+{{range .Issues}}  - {{.Title}} ({{.URL}})
+{{- if .DueDate}}
+    Due date: {{.DueDate}}
+{{- end}}
+{{- if .MergeRequestTitle}}
+    Merge request: {{.MergeRequestTitle}} ({{.MergeRequestURL}})
+{{- end}}
+{{end}}
+{{end}}
+{{- end}}
+{{- if .ToCloseIssues}}
+Issues to close this week:
+
+{{range .ToCloseIssues}}{{.ProjectName}}:
+{{range .Issues}}  - {{.Title}} ({{.URL}})
+{{- if .DueDate}}
+    Due date: {{.DueDate}}
+{{- end}}
+{{- if .MergeRequestTitle}}
+    Merge request: {{.MergeRequestTitle}} ({{.MergeRequestURL}})
+{{- end}}
+{{end}}
+{{end}}
+{{- end}}
+{{- if .MergedMergeRequests}}
+Merge requests merged {{.MergedWeeksLabel}}:
+
+{{range .MergedMergeRequests}}  - {{.Title}} ({{.URL}})
+{{end}}
+{{- end}}
+{{- if .ReviewedMergeRequests}}
+Reviews performed:
+
+{{range .ReviewedMergeRequests}}  - {{.Title}} ({{.URL}})
+{{end}}
+{{- end}}
+{{- if .CommitStats}}
+Contribution stats:
+
+{{range .CommitStats}}  - {{.ProjectName}}: {{.Commits}} commits, +{{.Additions}}/-{{.Deletions}} lines
+{{end}}
+{{- end}}
+{{- if .MainDifficulties}}
+Main difficulties:
+{{range .MainDifficulties}}  - {{.}}
+{{end}}
+{{- end}}
+`
+
+const defaultHTMLTemplate = `{{- if .ClosedIssues}}
+<h3>Issues closed {{.ClosedWeeksLabel}}</h3>
+{{range .ClosedIssues}}<h4>{{.ProjectName}}</h4>
+<ul>
+{{range .Issues}}  <li><a href="{{.URL}}">{{.Title}}</a>
+    <ul>
+    {{- if .DueDate}}
+      <li>Due date: {{.DueDate}}</li>
+    {{- end}}
+    {{- if .MergeRequestTitle}}
+      <li>Merge request: <a href="{{.MergeRequestURL}}">{{.MergeRequestTitle}}</a></li>
+    {{- end}}
+    </ul>
+  </li>
+{{end}}</ul>
+{{end}}
+{{- end}}
+{{- if .ToCloseIssues}}
+<h3>Issues to close this week</h3>
+{{range .ToCloseIssues}}<h4>{{.ProjectName}}</h4>
+<ul>
+{{range .Issues}}  <li><a href="{{.URL}}">{{.Title}}</a>
+    <ul>
+    {{- if .DueDate}}
+      <li>Due date: {{.DueDate}}</li>
+    {{- end}}
+    {{- if .MergeRequestTitle}}
+      <li>Merge request: <a href="{{.MergeRequestURL}}">{{.MergeRequestTitle}}</a></li>
+    {{- end}}
+    </ul>
+  </li>
+{{end}}</ul>
+{{end}}
+{{- end}}
+{{- if .MergedMergeRequests}}
+<h3>Merge requests merged {{.MergedWeeksLabel}}</h3>
+<ul>
+{{range .MergedMergeRequests}}  <li><a href="{{.URL}}">{{.Title}}</a></li>
+{{end}}</ul>
+{{- end}}
+{{- if .ReviewedMergeRequests}}
+<h3>Reviews performed</h3>
+<ul>
+{{range .ReviewedMergeRequests}}  <li><a href="{{.URL}}">{{.Title}}</a></li>
+{{end}}</ul>
+{{- end}}
+{{- if .CommitStats}}
+<h3>Contribution stats</h3>
+<ul>
+{{range .CommitStats}}  <li>{{.ProjectName}}: {{.Commits}} commits, +{{.Additions}}/-{{.Deletions}} lines</li>
+{{end}}</ul>
+{{- end}}
+{{- if .MainDifficulties}}
+<h3>Main difficulties</h3>
+<ul>
+{{range .MainDifficulties}}  <li>{{.}}</li>
+{{end}}</ul>
+{{- end}}
+`
+
+// loadTemplateSource returns the template text for format, preferring a
+// user-provided "<format>.tmpl" file in config.TemplateDir when present,
+// falling back to the built-in default otherwise.
+func loadTemplateSource(format string) (string, error) {
+	if config.TemplateDir != "" {
+		templatePath := filepath.Join(config.TemplateDir, format+".tmpl")
+		templateBytes, err := os.ReadFile(templatePath)
+		if err == nil {
+			return string(templateBytes), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("reading template %q: %w", templatePath, err)
+		}
+	}
+
+	switch format {
+	case "markdown":
+		return defaultMarkdownTemplate, nil
+	case "text":
+		return defaultTextTemplate, nil
+	case "html":
+		return defaultHTMLTemplate, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// renderReport renders data as the given format ("markdown", "text" or
+// "html"), using html/template (and its contextual auto-escaping) for html
+// and text/template for the rest.
+func renderReport(data ReportData, format string) (string, error) {
+	source, err := loadTemplateSource(format)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if format == "html" {
+		tmpl, err := htmltemplate.New(format).Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("parsing %s template: %w", format, err)
+		}
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return "", fmt.Errorf("rendering %s template: %w", format, err)
+		}
+	} else {
+		tmpl, err := texttemplate.New(format).Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("parsing %s template: %w", format, err)
+		}
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return "", fmt.Errorf("rendering %s template: %w", format, err)
+		}
+	}
+
+	return buf.String(), nil
+}
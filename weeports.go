@@ -2,22 +2,26 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/smtp"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/xanzy/go-gitlab"
+
+	"weeports/logging"
 )
 
 const (
@@ -25,8 +29,11 @@ const (
 )
 
 var (
-	config       Config
-	gitlabClient *gitlab.Client
+	config           Config
+	gitlabClient     *gitlab.Client
+	fetcher          *gitlabFetcher
+	currentUserName  string
+	currentUserEmail string
 )
 
 type Config struct {
@@ -38,6 +45,11 @@ type Config struct {
 	SMTPHost       string
 	SMTPPort       string
 	RecipientEmail string
+	Sinks          []SinkConfig
+	Schedule       string
+	Timezone       string
+	MaxConcurrency int
+	TemplateDir    string
 }
 
 func getConfigDir() string {
@@ -72,7 +84,7 @@ func openDefaultConfigFile() (*os.File, error) {
 	configDir := getConfigDir()
 	err := os.MkdirAll(configDir, os.ModePerm)
 	if err != nil {
-		log.Fatalf("Error mkdir'ing in readConfig: %s\n", err)
+		return nil, fmt.Errorf("error mkdir'ing in readConfig: %w", err)
 	}
 
 	configPath := filepath.Join(configDir, configFileName)
@@ -81,6 +93,38 @@ func openDefaultConfigFile() (*os.File, error) {
 	return configFile, err
 }
 
+// needsSMTPSink reports whether config will build an SMTPSink: either no
+// sinks are configured (the default falls back to SMTP) or a "smtp" entry
+// is explicitly listed.
+func needsSMTPSink(config *Config) bool {
+	if len(config.Sinks) == 0 {
+		return true
+	}
+	for _, sinkConfig := range config.Sinks {
+		if sinkConfig.Type == "smtp" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needsSMTPCredentials reports whether config will build a sink that
+// authenticates with SMTPUsername/SMTPPassword: an SMTPSink, or an
+// IMAPAppendSink, which reuses those same credentials to log in.
+func needsSMTPCredentials(config *Config) bool {
+	if needsSMTPSink(config) {
+		return true
+	}
+	for _, sinkConfig := range config.Sinks {
+		if sinkConfig.Type == "imap" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func checkConfigFields(config *Config) error {
 	if config.GitlabUrl == "" {
 		return errors.New("no GitLab URL specified in the config file")
@@ -91,30 +135,33 @@ func checkConfigFields(config *Config) error {
 	if config.GitlabUsername == "" {
 		return errors.New("no GitLab username specified in the config file")
 	}
-	if config.SMTPUsername == "" {
-		log.Fatalln("No SMTP username specified in the config file")
-	}
-	if config.SMTPPassword == "" {
-		log.Fatalln("No SMTP password specified in the config file")
-	}
-	if config.SMTPHost == "" {
-		log.Fatalln("No SMTP host specified in the config file")
-	}
-	if config.SMTPPort == "" {
-		log.Fatalln("No SMTP port specified in the config file")
+
+	if needsSMTPCredentials(config) {
+		if config.SMTPUsername == "" {
+			return errors.New("no SMTP username specified in the config file")
+		}
+		if config.SMTPPassword == "" {
+			return errors.New("no SMTP password specified in the config file")
+		}
 	}
-	if config.RecipientEmail == "" {
-		log.Fatalln("No recipient email specified in the config file")
+
+	if needsSMTPSink(config) {
+		if config.SMTPHost == "" {
+			return errors.New("no SMTP host specified in the config file")
+		}
+		if config.SMTPPort == "" {
+			return errors.New("no SMTP port specified in the config file")
+		}
+		if config.RecipientEmail == "" {
+			return errors.New("no recipient email specified in the config file")
+		}
 	}
 
 	return nil
 }
 
-func readConfig(configPath string) error {
-	var (
-		configFile *os.File
-		err        error
-	)
+func readConfig(configPath string) (err error) {
+	var configFile *os.File
 
 	if len(configPath) == 0 {
 		configFile, err = openDefaultConfigFile()
@@ -128,9 +175,9 @@ func readConfig(configPath string) error {
 		return err
 	}
 	defer func(configFile *os.File) {
-		err := configFile.Close()
-		if err != nil {
-			log.Fatal(err)
+		closeErr := configFile.Close()
+		if closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}(configFile)
 
@@ -149,27 +196,40 @@ func readConfig(configPath string) error {
 	return checkConfigFields(&config)
 }
 
-func setGitlabClient() {
+func setGitlabClient() error {
 	var err error
 	gitlabClient, err = gitlab.NewClient(config.GitlabToken, gitlab.WithBaseURL(config.GitlabUrl))
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("setting up GitLab client: %w", err)
+	}
+
+	fetcher = newGitlabFetcher(config)
+
+	user, _, err := gitlabClient.Users.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("fetching current GitLab user: %w", err)
 	}
+	currentUserName = user.Name
+	currentUserEmail = user.Email
+
+	return nil
 }
 
-func fetchClosedLastWeeksIssues(weeks int) []*gitlab.Issue {
+func fetchClosedLastWeeksIssues(ctx context.Context, weeks int) ([]*gitlab.Issue, error) {
 	nowTime := time.Now()
 	days := weeks * -7
-	searchOpts := &gitlab.ListIssuesOptions{
-		Scope:            gitlab.String("assigned_to_me"),
-		AssigneeUsername: &config.GitlabUsername,
-		UpdatedAfter:     gitlab.Time(nowTime.AddDate(0, 0, days)),
-		State:            gitlab.String("closed"),
-	}
 
-	issues, response, err := gitlabClient.Issues.ListIssues(searchOpts)
-	if err != nil || response.Status != "200 OK" {
-		log.Fatal(err)
+	issues, err := paginate(ctx, func(page int) ([]*gitlab.Issue, *gitlab.Response, error) {
+		return gitlabClient.Issues.ListIssues(&gitlab.ListIssuesOptions{
+			ListOptions:      gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			Scope:            gitlab.String("assigned_to_me"),
+			AssigneeUsername: &config.GitlabUsername,
+			UpdatedAfter:     gitlab.Time(nowTime.AddDate(0, 0, days)),
+			State:            gitlab.String("closed"),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching closed issues: %w", err)
 	}
 
 	for i := 0; i < len(issues); i++ {
@@ -180,40 +240,57 @@ func fetchClosedLastWeeksIssues(weeks int) []*gitlab.Issue {
 		}
 	}
 
-	return issues
+	return issues, nil
 }
 
-func fetchOpenIssuesOnDueDate(dueDate string) []*gitlab.Issue {
-	searchOpts := &gitlab.ListIssuesOptions{
-		Scope:            gitlab.String("assigned_to_me"),
-		AssigneeUsername: &config.GitlabUsername,
-		DueDate:          &dueDate,
-		State:            gitlab.String("opened"),
-	}
-	issues, response, err := gitlabClient.Issues.ListIssues(searchOpts)
-	if err != nil || response.StatusCode != 200 {
-		log.Fatal(err)
+func fetchOpenIssuesOnDueDate(ctx context.Context, dueDate string) ([]*gitlab.Issue, error) {
+	issues, err := paginate(ctx, func(page int) ([]*gitlab.Issue, *gitlab.Response, error) {
+		return gitlabClient.Issues.ListIssues(&gitlab.ListIssuesOptions{
+			ListOptions:      gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			Scope:            gitlab.String("assigned_to_me"),
+			AssigneeUsername: &config.GitlabUsername,
+			DueDate:          &dueDate,
+			State:            gitlab.String("opened"),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching open issues due %q: %w", dueDate, err)
 	}
 
-	return issues
+	return issues, nil
 }
 
-func fetchToCloseThisWeekIssues() []*gitlab.Issue {
+func fetchToCloseThisWeekIssues(ctx context.Context) ([]*gitlab.Issue, error) {
 	var issues []*gitlab.Issue
-	issues = append(issues, fetchOpenIssuesOnDueDate("week")...)
-	issues = append(issues, fetchOpenIssuesOnDueDate("overdue")...)
 
-	return issues
+	weekIssues, err := fetchOpenIssuesOnDueDate(ctx, "week")
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, weekIssues...)
+
+	overdueIssues, err := fetchOpenIssuesOnDueDate(ctx, "overdue")
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, overdueIssues...)
+
+	return issues, nil
 }
 
-func fetchProjectNameMap() map[int]string {
+func fetchProjectNameMap(ctx context.Context, weeks int) (map[int]string, error) {
 	nowTime := time.Now()
-	projects, response, err := gitlabClient.Projects.ListProjects(&gitlab.ListProjectsOptions{
-		Membership:        gitlab.Bool(true),
-		LastActivityAfter: gitlab.Time(nowTime.AddDate(0, 0, -7)),
+	days := weeks * -7
+
+	projects, err := paginate(ctx, func(page int) ([]*gitlab.Project, *gitlab.Response, error) {
+		return gitlabClient.Projects.ListProjects(&gitlab.ListProjectsOptions{
+			ListOptions:       gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			Membership:        gitlab.Bool(true),
+			LastActivityAfter: gitlab.Time(nowTime.AddDate(0, 0, days)),
+		})
 	})
-	if err != nil || response.StatusCode != 200 {
-		log.Fatal(err)
+	if err != nil {
+		return nil, fmt.Errorf("fetching project names: %w", err)
 	}
 
 	projectNameMap := make(map[int]string)
@@ -222,7 +299,7 @@ func fetchProjectNameMap() map[int]string {
 		projectNameMap[project.ID] = project.Name
 	}
 
-	return projectNameMap
+	return projectNameMap, nil
 }
 
 func groupIssuesByProject(issues []*gitlab.Issue) map[int][]*gitlab.Issue {
@@ -245,147 +322,471 @@ func slugify(inputString string) string {
 	return strings.ToLower(cleanedString)
 }
 
-func fetchIssueLastMergeRequest(issue *gitlab.Issue) *gitlab.MergeRequest {
-	listMergeRequestOptions := &gitlab.ListMergeRequestsOptions{
-		AuthorID: &issue.Assignee.ID,
-		State:    gitlab.String("opened"),
-	}
-	mergeRequests, response, err := gitlabClient.MergeRequests.ListMergeRequests(listMergeRequestOptions)
-	if err != nil || response.StatusCode != 200 {
-		log.Fatal(err)
+// fetchProjectMergeRequestsBySourceBranch fetches every open merge request
+// authored by config.GitlabUsername in a project once, and returns them
+// keyed by their slugified source branch, so callers can look up an issue's
+// merge request without one API call per issue.
+func fetchProjectMergeRequestsBySourceBranch(ctx context.Context, projectID int) (map[string]*gitlab.MergeRequest, error) {
+	mergeRequests, err := paginate(ctx, func(page int) ([]*gitlab.MergeRequest, *gitlab.Response, error) {
+		return gitlabClient.MergeRequests.ListProjectMergeRequests(projectID, &gitlab.ListProjectMergeRequestsOptions{
+			ListOptions:    gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			State:          gitlab.String("opened"),
+			AuthorUsername: &config.GitlabUsername,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching merge requests for project %d: %w", projectID, err)
 	}
 
-	issueTitleCleaned := slugify(issue.Title)
+	bySourceBranch := make(map[string]*gitlab.MergeRequest)
 	for i := 0; i < len(mergeRequests); i++ {
 		mergeRequest := mergeRequests[i]
-		sourceBranchCleaned := slugify(mergeRequest.SourceBranch)
-		if sourceBranchCleaned != issueTitleCleaned {
-			mergeRequest = nil
-			mergeRequests = slices.Delete(mergeRequests, i, i+1)
+		key := slugify(mergeRequest.SourceBranch)
+		existing, ok := bySourceBranch[key]
+		if !ok || mergeRequest.IID > existing.IID {
+			bySourceBranch[key] = mergeRequest
 		}
 	}
 
-	if len(mergeRequests) == 0 {
+	return bySourceBranch, nil
+}
+
+func buildGroupedIssuesData(ctx context.Context, groupedIssues map[int][]*gitlab.Issue, weeks int) ([]ProjectIssuesData, error) {
+	projectNameMap, err := fetchProjectNameMap(ctx, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	var projectIDs []int
+	for group := range groupedIssues {
+		projectIDs = append(projectIDs, group)
+	}
+
+	var mu sync.Mutex
+	mergeRequestsByProject := make(map[int]map[string]*gitlab.MergeRequest, len(projectIDs))
+	err = fetcher.forEach(ctx, projectIDs, func(ctx context.Context, projectID int) error {
+		mergeRequests, err := fetchProjectMergeRequestsBySourceBranch(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		mergeRequestsByProject[projectID] = mergeRequests
+		mu.Unlock()
+
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return mergeRequests[len(mergeRequests)-1]
-}
 
-func formatGroupedIssues(groupedIssues map[int][]*gitlab.Issue) string {
-	var issuesStrs []string
-	projectNameMap := fetchProjectNameMap()
+	var projectsData []ProjectIssuesData
 	for group, issueGroup := range groupedIssues {
 		if len(issueGroup) == 0 {
 			continue
 		}
-		issueStr := "#### " + projectNameMap[group] + ":\r\n"
+		mergeRequestsBySourceBranch := mergeRequestsByProject[group]
+		projectData := ProjectIssuesData{ProjectName: projectNameMap[group]}
 		for j := 0; j < len(issueGroup); j++ {
 			issue := issueGroup[j]
-			issueStr += "  * [" + issue.Title + "](" + issue.WebURL + ")\r\n"
-			dueDate := issue.DueDate
-			if dueDate != nil {
-				issueStr += "    * Due date: " + dueDate.String() + "\r\n"
+			issueData := IssueData{Title: issue.Title, URL: issue.WebURL}
+			if issue.DueDate != nil {
+				issueData.DueDate = issue.DueDate.String()
 			}
-			mergeRequest := fetchIssueLastMergeRequest(issue)
+			mergeRequest := mergeRequestsBySourceBranch[slugify(issue.Title)]
 			if mergeRequest != nil {
-				issueStr += "    * Merge request: [" + mergeRequest.Title + "](" + mergeRequest.WebURL + ")\r\n"
+				issueData.MergeRequestTitle = mergeRequest.Title
+				issueData.MergeRequestURL = mergeRequest.WebURL
+			}
+			projectData.Issues = append(projectData.Issues, issueData)
+		}
+		projectsData = append(projectsData, projectData)
+	}
+
+	return projectsData, nil
+}
+
+func fetchMergedLastWeeksMergeRequests(ctx context.Context, weeks int) ([]*gitlab.MergeRequest, error) {
+	nowTime := time.Now()
+	days := weeks * -7
+
+	mergeRequests, err := paginate(ctx, func(page int) ([]*gitlab.MergeRequest, *gitlab.Response, error) {
+		return gitlabClient.MergeRequests.ListMergeRequests(&gitlab.ListMergeRequestsOptions{
+			ListOptions:    gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			AuthorUsername: &config.GitlabUsername,
+			UpdatedAfter:   gitlab.Time(nowTime.AddDate(0, 0, days)),
+			State:          gitlab.String("merged"),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching merged merge requests: %w", err)
+	}
+
+	return mergeRequests, nil
+}
+
+func fetchReviewedMergeRequests(ctx context.Context, weeks int) ([]*gitlab.MergeRequest, error) {
+	nowTime := time.Now()
+	days := weeks * -7
+
+	mergeRequests, err := paginate(ctx, func(page int) ([]*gitlab.MergeRequest, *gitlab.Response, error) {
+		return gitlabClient.MergeRequests.ListMergeRequests(&gitlab.ListMergeRequestsOptions{
+			ListOptions:      gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+			ReviewerUsername: &config.GitlabUsername,
+			UpdatedAfter:     gitlab.Time(nowTime.AddDate(0, 0, days)),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching reviewed merge requests: %w", err)
+	}
+
+	return mergeRequests, nil
+}
+
+type projectCommitStats struct {
+	projectName string
+	commits     int
+	additions   int
+	deletions   int
+}
+
+func fetchCommitStats(ctx context.Context, weeks int) ([]projectCommitStats, error) {
+	nowTime := time.Now()
+	days := weeks * -7
+	sinceTime := nowTime.AddDate(0, 0, days)
+	projectNameMap, err := fetchProjectNameMap(ctx, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	var projectIDs []int
+	for projectID := range projectNameMap {
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	var mu sync.Mutex
+	var stats []projectCommitStats
+	err = fetcher.forEach(ctx, projectIDs, func(ctx context.Context, projectID int) error {
+		commits, err := paginate(ctx, func(page int) ([]*gitlab.Commit, *gitlab.Response, error) {
+			return gitlabClient.Commits.ListCommits(projectID, &gitlab.ListCommitsOptions{
+				ListOptions: gitlab.ListOptions{Page: page, PerPage: defaultPerPage},
+				Since:       &sinceTime,
+				All:         gitlab.Bool(true),
+				WithStats:   gitlab.Bool(true),
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("fetching commits for project %q: %w", projectNameMap[projectID], err)
+		}
+
+		projectStats := projectCommitStats{projectName: projectNameMap[projectID]}
+		seenCommits := make(map[string]bool, len(commits))
+		for i := 0; i < len(commits); i++ {
+			commit := commits[i]
+			if commit.AuthorName != currentUserName && commit.AuthorEmail != currentUserEmail {
+				continue
+			}
+			if seenCommits[commit.ID] {
+				// All:true walks every branch, so a commit reachable from more
+				// than one ref (e.g. an undeleted merged feature branch) would
+				// otherwise be counted, and its stats double-counted, per ref.
+				continue
+			}
+			seenCommits[commit.ID] = true
+
+			projectStats.commits++
+			if commit.Stats != nil {
+				projectStats.additions += commit.Stats.Additions
+				projectStats.deletions += commit.Stats.Deletions
 			}
 		}
-		issuesStrs = append(issuesStrs, issueStr)
+
+		if projectStats.commits > 0 {
+			mu.Lock()
+			stats = append(stats, projectStats)
+			mu.Unlock()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return strings.Join(issuesStrs, "")
+	return stats, nil
 }
 
-func formatClosedLastWeeksIssues(weeks int) string {
-	issues := fetchClosedLastWeeksIssues(weeks)
+func buildMergedLastWeeksMergeRequestsData(ctx context.Context, weeks int) (string, []MergeRequestData, error) {
+	mergeRequests, err := fetchMergedLastWeeksMergeRequests(ctx, weeks)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(mergeRequests) == 0 {
+		return "", nil, nil
+	}
+
+	weeksStr := "last week"
+	if weeks > 1 {
+		weeksStr = fmt.Sprintf("in the last %d weeks", weeks)
+	}
+
+	var data []MergeRequestData
+	for i := 0; i < len(mergeRequests); i++ {
+		mergeRequest := mergeRequests[i]
+		data = append(data, MergeRequestData{Title: mergeRequest.Title, URL: mergeRequest.WebURL})
+	}
+
+	return weeksStr, data, nil
+}
+
+func buildReviewedMergeRequestsData(ctx context.Context, weeks int) ([]MergeRequestData, error) {
+	mergeRequests, err := fetchReviewedMergeRequests(ctx, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []MergeRequestData
+	for i := 0; i < len(mergeRequests); i++ {
+		mergeRequest := mergeRequests[i]
+		data = append(data, MergeRequestData{Title: mergeRequest.Title, URL: mergeRequest.WebURL})
+	}
+
+	return data, nil
+}
+
+func buildCommitStatsData(ctx context.Context, weeks int) ([]CommitStatData, error) {
+	stats, err := fetchCommitStats(ctx, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []CommitStatData
+	for i := 0; i < len(stats); i++ {
+		stat := stats[i]
+		data = append(data, CommitStatData{
+			ProjectName: stat.projectName,
+			Commits:     stat.commits,
+			Additions:   stat.additions,
+			Deletions:   stat.deletions,
+		})
+	}
+
+	return data, nil
+}
+
+func buildClosedLastWeeksIssuesData(ctx context.Context, weeks int) (string, []ProjectIssuesData, error) {
+	issues, err := fetchClosedLastWeeksIssues(ctx, weeks)
+	if err != nil {
+		return "", nil, err
+	}
 	if len(issues) == 0 {
-		return ""
+		return "", nil, nil
 	}
 	groupedIssues := groupIssuesByProject(issues)
 	if len(groupedIssues) == 0 {
-		return ""
+		return "", nil, nil
 	}
 
 	weeksStr := "last week"
 	if weeks > 1 {
 		weeksStr = fmt.Sprintf("in the last %d weeks", weeks)
 	}
-	title := "### Issues closed " + weeksStr + ":\r\n\r\n"
-	body := formatGroupedIssues(groupedIssues)
+	data, err := buildGroupedIssuesData(ctx, groupedIssues, weeks)
+	if err != nil {
+		return "", nil, err
+	}
 
-	return title + body + "\r\n"
+	return weeksStr, data, nil
 }
 
-func formatToCloseThisWeekIssues() string {
-	issues := fetchToCloseThisWeekIssues()
+func buildToCloseThisWeekIssuesData(ctx context.Context) ([]ProjectIssuesData, error) {
+	issues, err := fetchToCloseThisWeekIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
 	if len(issues) == 0 {
-		return ""
+		return nil, nil
 	}
 	groupedIssues := groupIssuesByProject(issues)
 	if len(groupedIssues) == 0 {
-		return ""
+		return nil, nil
 	}
 
-	title := "### Issues to close this week:\r\n\r\n"
-	body := formatGroupedIssues(groupedIssues)
-
-	return title + body + "\r\n"
+	return buildGroupedIssuesData(ctx, groupedIssues, 1)
 }
 
-func readAndFormatMainDifficulties() string {
+func buildMainDifficulties() ([]string, error) {
 	inputReader := bufio.NewReader(os.Stdin)
-	mainDifficultiesStr := "### Main difficulties:"
-	fmt.Println(mainDifficultiesStr)
-	difficulties := ""
+	fmt.Println("### Main difficulties:")
+
+	var difficulties []string
 	for {
 		difficulty, err := inputReader.ReadString('\n')
-		if err != nil {
-			log.Fatal(err)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		trimmed := strings.TrimSpace(difficulty)
+		if trimmed == "" {
+			break
 		}
-		if len(strings.TrimSpace(difficulty)) == 0 {
+		difficulties = append(difficulties, trimmed)
+		if err == io.EOF {
 			break
 		}
-		difficulties += "  * " + difficulty
 	}
-	if len(strings.TrimSpace(difficulties)) == 0 {
-		return ""
+
+	return difficulties, nil
+}
+
+func sendReport(data ReportData, format string) error {
+	nowString := time.Now().Format("2006-01-02")
+	subject := "Weekly report (" + nowString + ")"
+
+	sinks, err := buildSinks(config)
+	if err != nil {
+		return err
+	}
+
+	body, err := renderReport(data, format)
+	if err != nil {
+		return err
+	}
+
+	var plainBody string
+	if format == "html" {
+		plainBody, err = renderReport(data, "text")
+		if err != nil {
+			return err
+		}
 	}
 
-	return mainDifficultiesStr + "\r\n" + difficulties + "\r\n"
+	var sendErrs []error
+	for _, sink := range sinks {
+		var err error
+		if format == "html" {
+			if sender, ok := sink.(htmlSender); ok {
+				err = sender.SendHTML(subject, plainBody, body)
+			} else {
+				err = sink.Send(subject, plainBody)
+			}
+		} else {
+			err = sink.Send(subject, body)
+		}
+
+		if err != nil {
+			sendErrs = append(sendErrs, err)
+			continue
+		}
+		logging.Infof("Report sent via %T", sink)
+	}
+
+	return errors.Join(sendErrs...)
 }
 
-func sendEmail(msgBody string) {
-	host := config.SMTPHost
-	toStr := config.RecipientEmail
-	to := []string{toStr}
-	nowTime := time.Now()
-	nowString := nowTime.Format("2006-01-02")
-	message := []byte("To: " + toStr + "\r\n" +
-		"Subject: Weekly report (" + nowString + ")\r\n" +
-		"\r\n" + msgBody + "\r\n")
+// RunOnce generates and sends a single weekly report. When interactive is
+// false (daemon mode) the "main difficulties" stdin prompt is skipped, since
+// there is no one around to answer it.
+func RunOnce(ctx context.Context, weeks int, interactive bool, format string) error {
+	var data ReportData
+	var err error
+
+	data.ClosedWeeksLabel, data.ClosedIssues, err = buildClosedLastWeeksIssuesData(ctx, weeks)
+	if err != nil {
+		return err
+	}
+
+	data.ToCloseIssues, err = buildToCloseThisWeekIssuesData(ctx)
+	if err != nil {
+		return err
+	}
+
+	data.MergedWeeksLabel, data.MergedMergeRequests, err = buildMergedLastWeeksMergeRequestsData(ctx, weeks)
+	if err != nil {
+		return err
+	}
+
+	data.ReviewedMergeRequests, err = buildReviewedMergeRequestsData(ctx, weeks)
+	if err != nil {
+		return err
+	}
 
-	auth := smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, host)
-	err := smtp.SendMail(host+":"+config.SMTPPort, auth, config.SMTPUsername, to, message)
+	data.CommitStats, err = buildCommitStatsData(ctx, weeks)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if interactive {
+		data.MainDifficulties, err = buildMainDifficulties()
+		if err != nil {
+			return err
+		}
 	}
-	log.Println("Email sent: " + string(message))
+
+	return sendReport(data, format)
+}
+
+func runDaemon(weeks int, format string) error {
+	if config.Schedule == "" {
+		return errors.New("no Schedule specified in the config file for daemon mode")
+	}
+	timezone := config.Timezone
+	if timezone == "" {
+		timezone = "Local"
+	}
+
+	scheduler, err := newScheduler(config.Schedule, timezone, func(ctx context.Context) error {
+		return RunOnce(ctx, weeks, false, format)
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logging.Infof("Starting weeports in daemon mode, schedule: %s", config.Schedule)
+	scheduler.Run(ctx)
+	logging.Infof("Shutting down weeports")
+
+	return nil
 }
 
 func main() {
+	defer logging.PanicHandler()
+
 	configPathPtr := flag.String("config", "", "Path to the configuration file")
 	weeksPtr := flag.Int("weeks", 1, "Number of weeks to report")
+	daemonPtr := flag.Bool("daemon", false, "Keep running and generate the report on a schedule instead of once")
+	formatPtr := flag.String("format", "markdown", "Report format: markdown, html or text")
+	logLevelPtr := flag.String("log-level", "", "Log level: trace, debug, info, warn, error, fatal, off (default: info when stdout is redirected, off otherwise)")
+	logFilePtr := flag.String("log-file", "", "Path to a file to write logs to (default: stderr)")
 	flag.Parse()
 
-	err := readConfig(*configPathPtr)
+	err := logging.Configure(*logLevelPtr, *logFilePtr)
+	if err != nil {
+		logging.Fatalf("%s", err)
+	}
+
+	err = readConfig(*configPathPtr)
+	if err != nil {
+		logging.Fatalf("%s", err)
+	}
+
+	err = setGitlabClient()
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatalf("%s", err)
 	}
-	setGitlabClient()
 
-	closedLastWeeksIssuesStr := formatClosedLastWeeksIssues(*weeksPtr)
-	toCloseWeekIssuesStr := formatToCloseThisWeekIssues()
-	mainDifficulties := readAndFormatMainDifficulties()
-	sendEmail(closedLastWeeksIssuesStr + toCloseWeekIssuesStr + mainDifficulties)
+	if *daemonPtr {
+		err = runDaemon(*weeksPtr, *formatPtr)
+		if err != nil {
+			logging.Fatalf("%s", err)
+		}
+		return
+	}
+
+	err = RunOnce(context.Background(), *weeksPtr, true, *formatPtr)
+	if err != nil {
+		logging.Fatalf("%s", err)
+	}
 }